@@ -0,0 +1,151 @@
+package bugsnag
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"sync"
+	"sync/atomic"
+)
+
+// ErrPacketDropped is returned by NotifyAsync when an event could not be
+// queued, either because the queue is full or the async pipeline hasn't
+// been started.
+var ErrPacketDropped = errors.New("bugsnag: packet dropped")
+
+// Stats are running counters for the async pipeline, useful for
+// observability (e.g. exporting as metrics).
+type Stats struct {
+	Sent    int64
+	Dropped int64
+	Failed  int64
+}
+
+// pipeline holds the background worker state for a started Client. It is
+// created by Start and torn down by Close.
+type pipeline struct {
+	events  chan *Event
+	wg      sync.WaitGroup
+	sent    int64
+	dropped int64
+	failed  int64
+}
+
+// Start spawns workers background goroutines that consume events from a
+// buffered channel of size queueSize and send them synchronously, so that
+// NotifyAsync never blocks the caller on network I/O. It is safe to call
+// from CapturePanic and other hot paths. SampleRate, if set, must be
+// between 0 and 1 inclusive.
+func (c *Client) Start(workers int, queueSize int) error {
+	if c.SampleRate < 0 || c.SampleRate > 1 {
+		return fmt.Errorf("Invalid SampleRate: %v (must be in [0, 1])", c.SampleRate)
+	}
+
+	c.pipelineMu.Lock()
+	defer c.pipelineMu.Unlock()
+
+	if c.pipeline != nil {
+		return fmt.Errorf("Client is already started")
+	}
+
+	p := &pipeline{events: make(chan *Event, queueSize)}
+	c.pipeline = p
+
+	for i := 0; i < workers; i++ {
+		p.wg.Add(1)
+		go c.worker(p)
+	}
+	return nil
+}
+
+func (c *Client) worker(p *pipeline) {
+	defer p.wg.Done()
+	for event := range p.events {
+		if err := c.send([]*Event{event}); err != nil {
+			atomic.AddInt64(&p.failed, 1)
+		} else {
+			atomic.AddInt64(&p.sent, 1)
+		}
+	}
+}
+
+// NotifyAsync enqueues event for background delivery and returns
+// immediately. It returns ErrPacketDropped if the pipeline hasn't been
+// started via Start or its queue is full. If SampleRate is set, events are
+// probabilistically dropped (silently, not counted against Dropped) before
+// ever reaching the queue.
+func (c *Client) NotifyAsync(event *Event) error {
+	if !c.shouldNotify(event) {
+		return nil
+	}
+	if c.SampleRate > 0 && c.SampleRate < 1 && rand.Float64() >= c.SampleRate {
+		return nil
+	}
+	if err := c.runBeforeNotify(event); err != nil {
+		if err == ErrSkipEvent {
+			return nil
+		}
+		return err
+	}
+
+	// Held for the enqueue itself (not just the nil check) so Close can't
+	// close p.events out from under a concurrent send on the same pipeline.
+	c.pipelineMu.RLock()
+	defer c.pipelineMu.RUnlock()
+
+	p := c.pipeline
+	if p == nil {
+		return ErrPacketDropped
+	}
+
+	select {
+	case p.events <- event:
+		return nil
+	default:
+		atomic.AddInt64(&p.dropped, 1)
+		return ErrPacketDropped
+	}
+}
+
+// Close stops accepting new events and waits for queued ones to flush, or
+// until ctx is done, whichever comes first.
+func (c *Client) Close(ctx context.Context) error {
+	c.pipelineMu.Lock()
+	p := c.pipeline
+	if p == nil {
+		c.pipelineMu.Unlock()
+		return nil
+	}
+	c.pipeline = nil
+	close(p.events)
+	c.pipelineMu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		p.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Stats returns a snapshot of the async pipeline's counters.
+func (c *Client) Stats() Stats {
+	c.pipelineMu.RLock()
+	p := c.pipeline
+	c.pipelineMu.RUnlock()
+	if p == nil {
+		return Stats{}
+	}
+	return Stats{
+		Sent:    atomic.LoadInt64(&p.sent),
+		Dropped: atomic.LoadInt64(&p.dropped),
+		Failed:  atomic.LoadInt64(&p.failed),
+	}
+}
@@ -0,0 +1,151 @@
+package bugsnag
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+func newAsyncTestClient(handler http.HandlerFunc) (*Client, *httptest.Server) {
+	server := httptest.NewServer(handler)
+	c := &Client{
+		APIKey:              "testkey",
+		ReleaseStage:        "production",
+		NotifyReleaseStages: []string{"production"},
+		Notifier:            DefaultNotifier,
+		UseSSL:              false,
+		Url:                 server.Listener.Addr().String(),
+		HTTPClient:          server.Client(),
+	}
+	return c, server
+}
+
+func TestNotifyAsyncDeliversToWorker(t *testing.T) {
+	received := make(chan struct{}, 1)
+	c, server := newAsyncTestClient(func(w http.ResponseWriter, r *http.Request) {
+		received <- struct{}{}
+		w.WriteHeader(http.StatusOK)
+	})
+	defer server.Close()
+
+	if err := c.Start(1, 10); err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close(context.Background())
+
+	event := c.New(nil)
+	if err := c.NotifyAsync(event); err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case <-received:
+	case <-time.After(time.Second):
+		t.Fatal("timed out waiting for the worker to deliver the event")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	if err := c.Close(ctx); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestNotifyAsyncWithoutStartDropsPacket(t *testing.T) {
+	c := &Client{ReleaseStage: "production", NotifyReleaseStages: []string{"production"}}
+	if err := c.NotifyAsync(c.New(nil)); err != ErrPacketDropped {
+		t.Fatalf("expected ErrPacketDropped, got %v", err)
+	}
+}
+
+func TestNotifyAsyncDropsWhenQueueFull(t *testing.T) {
+	block := make(chan struct{})
+	entered := make(chan struct{}, 1)
+	c, server := newAsyncTestClient(func(w http.ResponseWriter, r *http.Request) {
+		entered <- struct{}{}
+		<-block
+		w.WriteHeader(http.StatusOK)
+	})
+	defer server.Close()
+
+	if err := c.Start(1, 1); err != nil {
+		t.Fatal(err)
+	}
+
+	// First event occupies the single worker (blocked on the handler, as
+	// confirmed by <-entered); the second fills the queue; the third has
+	// nowhere to go.
+	if err := c.NotifyAsync(c.New(nil)); err != nil {
+		t.Fatal(err)
+	}
+	<-entered
+	if err := c.NotifyAsync(c.New(nil)); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.NotifyAsync(c.New(nil)); err != ErrPacketDropped {
+		t.Fatalf("expected ErrPacketDropped once queue and worker are full, got %v", err)
+	}
+
+	if stats := c.Stats(); stats.Dropped != 1 {
+		t.Fatalf("expected Dropped=1, got %+v", stats)
+	}
+
+	close(block)
+	if err := c.Close(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestNotifyAsyncRejectsInvalidSampleRate(t *testing.T) {
+	c := &Client{SampleRate: 1.5}
+	if err := c.Start(1, 1); err == nil {
+		t.Fatal("expected an error for a SampleRate outside [0, 1]")
+	}
+}
+
+// TestNotifyAsyncConcurrentWithCloseDoesNotRace exercises NotifyAsync
+// running concurrently with Close, as happens in practice when
+// CapturePanic fires on one goroutine while another is shutting the
+// process down. Run with -race: a send on a channel Close has already
+// closed panics the whole process, not just the test.
+func TestNotifyAsyncConcurrentWithCloseDoesNotRace(t *testing.T) {
+	c, server := newAsyncTestClient(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+	defer server.Close()
+
+	if err := c.Start(4, 16); err != nil {
+		t.Fatal(err)
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			c.NotifyAsync(c.New(nil))
+		}()
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	if err := c.Close(ctx); err != nil && err != context.DeadlineExceeded {
+		t.Fatal(err)
+	}
+	wg.Wait()
+}
+
+func TestStartTwiceReturnsError(t *testing.T) {
+	c := &Client{}
+	if err := c.Start(1, 1); err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close(context.Background())
+
+	if err := c.Start(1, 1); err == nil {
+		t.Fatal("expected an error starting an already-started client")
+	}
+}
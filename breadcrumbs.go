@@ -0,0 +1,101 @@
+package bugsnag
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// defaultMaxBreadcrumbs is used when Client.MaxBreadcrumbs is unset.
+const defaultMaxBreadcrumbs = 25
+
+// Breadcrumb is a single entry in a request's timeline of events leading
+// up to an error, attached to events via NewWithContext.
+type Breadcrumb struct {
+	Timestamp time.Time              `json:"timestamp"`
+	Name      string                 `json:"name"`
+	Type      string                 `json:"type"`
+	Metadata  map[string]interface{} `json:"metaData,omitempty"`
+}
+
+// breadcrumbTrail is a fixed-size ring buffer of breadcrumbs, carried
+// around on a context.Context so it can be filled in from anywhere a
+// request flows through and read back when an event is built.
+type breadcrumbTrail struct {
+	mu      sync.Mutex
+	max     int
+	entries []Breadcrumb
+}
+
+func (t *breadcrumbTrail) leave(b Breadcrumb) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.entries = append(t.entries, b)
+	if len(t.entries) > t.max {
+		t.entries = t.entries[len(t.entries)-t.max:]
+	}
+}
+
+func (t *breadcrumbTrail) snapshot() []Breadcrumb {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	out := make([]Breadcrumb, len(t.entries))
+	copy(out, t.entries)
+	return out
+}
+
+type breadcrumbsKey struct{}
+
+// WithBreadcrumbs returns a context carrying a fresh breadcrumb trail,
+// capped at DefaultClient.MaxBreadcrumbs, so Leave and NewWithContext can
+// record and later attach a timeline of events to a reported error.
+func WithBreadcrumbs(ctx context.Context) context.Context {
+	max := DefaultClient.MaxBreadcrumbs
+	if max <= 0 {
+		max = defaultMaxBreadcrumbs
+	}
+	return context.WithValue(ctx, breadcrumbsKey{}, &breadcrumbTrail{max: max})
+}
+
+// Leave appends a breadcrumb to the trail carried by ctx. It is a no-op if
+// ctx has no trail, i.e. WithBreadcrumbs was never called on it.
+func Leave(ctx context.Context, name string, typ string, metadata map[string]interface{}) {
+	trail, _ := ctx.Value(breadcrumbsKey{}).(*breadcrumbTrail)
+	if trail == nil {
+		return
+	}
+	trail.leave(Breadcrumb{
+		Timestamp: time.Now(),
+		Name:      name,
+		Type:      typ,
+		Metadata:  metadata,
+	})
+}
+
+// NewWithContext is like New, but also attaches the breadcrumb trail
+// carried by ctx, if any, to the event.
+func (c *Client) NewWithContext(ctx context.Context, err error) *Event {
+	event := c.New(err)
+	if trail, ok := ctx.Value(breadcrumbsKey{}).(*breadcrumbTrail); ok {
+		event.Breadcrumbs = trail.snapshot()
+	}
+	return event
+}
+
+// Handler wraps next with middleware that installs a fresh breadcrumb
+// trail for the request, leaves a request-received breadcrumb, and
+// reports panics via CapturePanic with that trail attached -- replacing
+// the common `defer CapturePanic(r)` boilerplate with request timeline
+// context for free.
+func Handler(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := WithBreadcrumbs(r.Context())
+		r = r.WithContext(ctx)
+		Leave(ctx, r.URL.Path, "request", map[string]interface{}{
+			"method": r.Method,
+		})
+		defer CapturePanic(r)
+		next.ServeHTTP(w, r)
+	})
+}
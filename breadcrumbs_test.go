@@ -0,0 +1,71 @@
+package bugsnag
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestLeaveIsNoopWithoutBreadcrumbContext(t *testing.T) {
+	// Should not panic even though the context carries no trail.
+	Leave(context.TODO(), "click", "user", nil)
+}
+
+func TestNewWithContextAttachesBreadcrumbs(t *testing.T) {
+	ctx := WithBreadcrumbs(context.Background())
+	Leave(ctx, "db.query", "request", map[string]interface{}{"table": "users"})
+	Leave(ctx, "cache.miss", "request", nil)
+
+	event := DefaultClient.NewWithContext(ctx, errors.New("boom"))
+
+	if len(event.Breadcrumbs) != 2 {
+		t.Fatalf("expected 2 breadcrumbs, got %d", len(event.Breadcrumbs))
+	}
+	if event.Breadcrumbs[0].Name != "db.query" || event.Breadcrumbs[1].Name != "cache.miss" {
+		t.Fatalf("breadcrumbs out of order: %+v", event.Breadcrumbs)
+	}
+}
+
+func TestBreadcrumbTrailEvictsOldest(t *testing.T) {
+	orig := DefaultClient.MaxBreadcrumbs
+	DefaultClient.MaxBreadcrumbs = 2
+	defer func() { DefaultClient.MaxBreadcrumbs = orig }()
+
+	ctx := WithBreadcrumbs(context.Background())
+	Leave(ctx, "one", "test", nil)
+	Leave(ctx, "two", "test", nil)
+	Leave(ctx, "three", "test", nil)
+
+	event := DefaultClient.NewWithContext(ctx, errors.New("boom"))
+	if len(event.Breadcrumbs) != 2 {
+		t.Fatalf("expected MaxBreadcrumbs to cap the trail at 2, got %d", len(event.Breadcrumbs))
+	}
+	if event.Breadcrumbs[0].Name != "two" || event.Breadcrumbs[1].Name != "three" {
+		t.Fatalf("expected the oldest breadcrumb to be evicted, got %+v", event.Breadcrumbs)
+	}
+}
+
+func TestHandlerInstallsBreadcrumbTrailAndRecoversPanics(t *testing.T) {
+	var sawBreadcrumb bool
+	handler := Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		event := DefaultClient.NewWithContext(ctx, errors.New("boom"))
+		sawBreadcrumb = len(event.Breadcrumbs) == 1 && event.Breadcrumbs[0].Type == "request"
+		panic("handler exploded")
+	}))
+
+	r := httptest.NewRequest("GET", "/widgets", nil)
+	w := httptest.NewRecorder()
+
+	defer func() {
+		if recover() == nil {
+			t.Fatal("expected Handler to re-panic after capturing")
+		}
+		if !sawBreadcrumb {
+			t.Fatal("expected Handler to leave a request-received breadcrumb before calling next")
+		}
+	}()
+	handler.ServeHTTP(w, r)
+}
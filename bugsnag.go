@@ -1,15 +1,15 @@
 package bugsnag
 
 import (
-	"bytes"
 	"encoding/json"
 	"errors"
-	"fmt"
+	"log"
 	"net/http"
 	"os"
-	"reflect"
+	"regexp"
 	"runtime"
 	"strings"
+	"sync"
 )
 
 const bugsnagURL string = "notify.bugsnag.com"
@@ -22,6 +22,11 @@ func hostname() string {
 
 func init() {
 	DefaultClient.APIKey = os.Getenv("BUGSNAG_APIKEY")
+	if dsn := os.Getenv("BUGSNAG_DSN"); dsn != "" {
+		if err := DefaultClient.ParseDSN(dsn); err != nil {
+			log.Printf("bugsnag: ignoring invalid BUGSNAG_DSN: %v", err)
+		}
+	}
 }
 
 var (
@@ -65,6 +70,45 @@ type Client struct {
 	Notifier            *Notifier
 	DefaultContext      string
 	App                 *App
+
+	// SampleRate, if non-zero, is the probability (0..1) that an event
+	// passed to NotifyAsync is actually sent, for cheaply downsampling
+	// high-volume errors.
+	SampleRate float64
+
+	// HTTPClient is used to deliver events. If nil, a client with
+	// defaultTimeout is used. Inject your own to add proxies, custom
+	// transports, or shorter/longer timeouts.
+	HTTPClient *http.Client
+
+	// MaxRetries is the number of times a send is retried, with
+	// exponential backoff, after a network failure or any response other
+	// than 200 that is not a PermanentError status (a 5xx, but also e.g.
+	// 403, 404 or 429). Zero means defaultMaxRetries.
+	MaxRetries int
+
+	// MaxBreadcrumbs caps the number of breadcrumbs kept per request
+	// trail, evicting the oldest first. Zero means defaultMaxBreadcrumbs.
+	// Only DefaultClient's value is read, by WithBreadcrumbs when it
+	// creates a trail; on any other Client this field has no effect.
+	MaxBreadcrumbs int
+
+	// BeforeNotify is a chain of filters run, in order, on every event
+	// before it is sent. A filter returns ErrSkipEvent to drop the event,
+	// or any other error to abort notification. This is also where
+	// per-app enrichment (build SHA, pod name, trace IDs, ...) hooks in.
+	BeforeNotify []func(*Event) error
+
+	// ScrubKeys and ScrubParams configure automatic PII redaction of
+	// Event.MetaData: any key matching one of ScrubKeys (case-insensitive)
+	// or one of the ScrubParams patterns has its value replaced with
+	// "[FILTERED]" before the event is sent.
+	ScrubKeys   []string
+	ScrubParams []*regexp.Regexp
+
+	pipelineMu      sync.RWMutex
+	pipeline        *pipeline
+	stackExtractors []StackExtractor
 }
 
 type App struct {
@@ -102,16 +146,45 @@ type Stacktrace struct {
 	InProject  bool   `json:"inProject,omitempty"`
 }
 
+// Severity is the Bugsnag event severity, one of SeverityInfo,
+// SeverityWarning or SeverityError.
+type Severity string
+
+const (
+	SeverityInfo    Severity = "info"
+	SeverityWarning Severity = "warning"
+	SeverityError   Severity = "error"
+)
+
+// User identifies the person affected by an event.
+type User struct {
+	ID    string `json:"id,omitempty"`
+	Email string `json:"email,omitempty"`
+	Name  string `json:"name,omitempty"`
+}
+
+// Session correlates an event with the session it occurred during, so
+// Bugsnag can compute unhandled-error rates.
+type Session struct {
+	ID        string `json:"id"`
+	StartedAt string `json:"startedAt"`
+}
+
 type Event struct {
-	UserID         string                            `json:"userId,omitempty"`
 	PayloadVersion string                            `json:"payloadVersion"`
 	App            *App                              `json:"app,omitempty"`
 	Device         *Device                           `json:"device,omitempty"`
 	OSVersion      string                            `json:"osVersion,omitempty"`
 	ReleaseStage   string                            `json:"releaseStage"`
 	Context        string                            `json:"context,omitempty"`
+	Severity       Severity                          `json:"severity,omitempty"`
+	GroupingHash   string                            `json:"groupingHash,omitempty"`
+	Unhandled      bool                              `json:"unhandled,omitempty"`
+	User           *User                             `json:"user,omitempty"`
+	Session        *Session                          `json:"session,omitempty"`
 	Exceptions     []Exception                       `json:"exceptions"`
 	MetaData       map[string]map[string]interface{} `json:"metaData,omitempty"`
+	Breadcrumbs    []Breadcrumb                      `json:"breadcrumbs,omitempty"`
 }
 
 type StacktraceFunc func(traces []Stacktrace) []Stacktrace
@@ -134,63 +207,19 @@ func encode(payload interface{}, indent bool) ([]byte, error) {
 	return b, nil
 }
 
-func (c *Client) send(events []*Event) error {
-
-	if c.APIKey == "" {
-		return fmt.Errorf("No Api Key Provided")
-	}
-
-	payload := &Payload{
-		Notifier: c.Notifier,
-		APIKey:   c.APIKey,
-		Events:   events,
-	}
-
-	protocol := "http://"
-	if c.UseSSL {
-		protocol = "https://"
-	}
-
-	b, err := encode(payload, c.Indent)
-	if err != nil {
-		return err
-	}
-	resp, err := http.Post(protocol+c.Url, applicationJson, bytes.NewBuffer(b))
-	if err != nil {
-		return err
-	}
-	// Always close a response's Body (which is always non-nil if err==nil)
-	defer resp.Body.Close()
-
-	if resp.StatusCode != 200 {
-		return fmt.Errorf("Unexpected status code: %d", resp.StatusCode)
-	}
-	return nil
-}
-
 // New returns returns a bugsnag Event instance, that can be further configured
 // before sending it.
 func (c *Client) New(err error) *Event {
 	return &Event{
-		PayloadVersion: "2",
+		PayloadVersion: "4",
 		OSVersion:      c.OSVersion,
 		ReleaseStage:   c.ReleaseStage,
 		App:            c.App,
 		// XXX Context
-		// XXX GroupingHash
-		// XXX Severity
-
-		// XXX USER suport
 
 		// AppVersion
 
-		Exceptions: []Exception{
-			Exception{
-				ErrorClass: reflect.TypeOf(err).String(),
-				Message:    err.Error(),
-				Stacktrace: stacktrace(3),
-			},
-		},
+		Exceptions: c.buildExceptions(err),
 	}
 }
 
@@ -206,9 +235,50 @@ func SetApp(app *App) {
 	DefaultClient.SetApp(app)
 }
 
-// WithUserID sets the user_id property on the bugsnag event.
+// WithUserID sets the user's ID on the bugsnag event.
+//
+// Deprecated: use WithUser instead.
 func (event *Event) WithUserID(userID string) *Event {
-	event.UserID = userID
+	if event.User == nil {
+		event.User = &User{}
+	}
+	event.User.ID = userID
+	return event
+}
+
+// WithUser sets the user affected by this event.
+func (event *Event) WithUser(user User) *Event {
+	event.User = &user
+	return event
+}
+
+// WithSession attaches the session this event occurred during, so
+// Bugsnag can compute unhandled-error rates for it.
+func (event *Event) WithSession(session Session) *Event {
+	event.Session = &session
+	return event
+}
+
+// WithSeverity sets the event's severity (SeverityInfo, SeverityWarning or
+// SeverityError).
+func (event *Event) WithSeverity(severity Severity) *Event {
+	event.Severity = severity
+	return event
+}
+
+// WithGroupingHash overrides how Bugsnag groups this event with others,
+// for errors whose default grouping (by stacktrace) doesn't separate
+// distinct issues correctly.
+func (event *Event) WithGroupingHash(hash string) *Event {
+	event.GroupingHash = hash
+	return event
+}
+
+// WithUnhandled marks whether this event was an unhandled panic, as
+// opposed to an error the app noticed and reported itself. It drives
+// Bugsnag's unhandled-error rate.
+func (event *Event) WithUnhandled(unhandled bool) *Event {
+	event.Unhandled = unhandled
 	return event
 }
 
@@ -238,18 +308,33 @@ func (event *Event) WithMetaData(tab string, name string, value interface{}) *Ev
 	return event
 }
 
-// Notify sends the configured event off to bugsnag.
-func (c *Client) Notify(event *Event) error {
+// shouldNotify reports whether event.ReleaseStage is one of the stages the
+// client is configured to report, and stamps it with the host metadata.
+func (c *Client) shouldNotify(event *Event) bool {
 	for _, stage := range c.NotifyReleaseStages {
 		if stage == event.ReleaseStage {
 			if c.Hostname != "" {
 				// Custom metadata to know what machine is reporting the error.
 				event.WithMetaData("host", "name", c.Hostname)
 			}
-			return c.send([]*Event{event})
+			return true
 		}
 	}
-	return nil
+	return false
+}
+
+// Notify sends the configured event off to bugsnag.
+func (c *Client) Notify(event *Event) error {
+	if !c.shouldNotify(event) {
+		return nil
+	}
+	if err := c.runBeforeNotify(event); err != nil {
+		if err == ErrSkipEvent {
+			return nil
+		}
+		return err
+	}
+	return c.send([]*Event{event})
 }
 
 func New(e error) *Event {
@@ -270,7 +355,7 @@ func Notify(e *Event) error {
 // URL as the event context
 // and marshals down the request content.
 func NotifyRequestError(err error, r *http.Request) error {
-	event := DefaultClient.New(err).WithContext(r.URL.String()).WithMetaData("request", "dump", r)
+	event := DefaultClient.NewWithContext(r.Context(), err).WithContext(r.URL.String()).WithMetaData("request", "dump", r)
 	return DefaultClient.Notify(event)
 }
 
@@ -322,13 +407,80 @@ func stacktrace(skip int) []Stacktrace {
 	return stacktrace
 }
 
+// packagePath is this package's own import path, used by callerStacktrace
+// to recognize (and skip) frames internal to it.
+var packagePath = importPathOf(currentFuncName())
+
+func currentFuncName() string {
+	pc, _, _, _ := runtime.Caller(0)
+	if fn := runtime.FuncForPC(pc); fn != nil {
+		return fn.Name()
+	}
+	return ""
+}
+
+// importPathOf extracts the package import path from a fully qualified
+// runtime function name, e.g. "github.com/x/y.(*Client).Foo" ->
+// "github.com/x/y". It looks for the first "." after the last "/" so
+// that method receivers (which contain their own dots) don't confuse it.
+func importPathOf(funcName string) string {
+	base := funcName
+	offset := 0
+	if slash := strings.LastIndex(funcName, "/"); slash >= 0 {
+		base = funcName[slash:]
+		offset = slash
+	}
+	if dot := strings.Index(base, "."); dot >= 0 {
+		return funcName[:offset+dot]
+	}
+	return funcName
+}
+
+// callerStacktrace captures the stack starting at the first frame outside
+// this package, so the trace reflects the real call site regardless of
+// how many wrapper functions inside bugsnag (New, NewWithContext,
+// buildExceptions, CapturePanic, ...) sit between it and the caller.
+func callerStacktrace() []Stacktrace {
+	skip := 0
+	for i := 0; ; i++ {
+		pc, _, _, ok := runtime.Caller(i)
+		if !ok {
+			break
+		}
+		fn := runtime.FuncForPC(pc)
+		name := ""
+		if fn != nil {
+			name = fn.Name()
+		}
+		// runtime.gopanic sits between a panicking function and its
+		// deferred calls (e.g. CapturePanic), so it must be skipped too,
+		// or the trace would start at the runtime itself.
+		if strings.HasPrefix(name, packagePath+".") || name == "runtime.gopanic" {
+			skip = i + 1
+			continue
+		}
+		break
+	}
+	// +1: stacktrace() is itself one more frame below us, so its own
+	// Caller(i) numbering is shifted by one relative to ours.
+	return stacktrace(skip + 1)
+}
+
 // CapturePanic reports panics happening while processing an HTTP request
 func CapturePanic(r *http.Request) {
 	if recovered := recover(); recovered != nil {
-		if err, ok := recovered.(error); ok {
-			NotifyRequestError(err, r)
-		} else if err, ok := recovered.(string); ok {
-			NotifyRequestError(errors.New(err), r)
+		var err error
+		if e, ok := recovered.(error); ok {
+			err = e
+		} else if s, ok := recovered.(string); ok {
+			err = errors.New(s)
+		}
+		if err != nil {
+			event := DefaultClient.NewWithContext(r.Context(), err).
+				WithContext(r.URL.String()).
+				WithMetaData("request", "dump", r).
+				WithUnhandled(true)
+			DefaultClient.Notify(event)
 		}
 		panic(recovered)
 	}
@@ -27,7 +27,7 @@ func TestNotifyRequest(t *testing.T) {
 	e := errors.New("This is a test")
 	if r, err := http.NewRequest("GET", "some URL", nil); err != nil {
 		t.Fatal(err)
-	} else if err := NotifyErrorRequest(e, r); err != nil {
+	} else if err := NotifyRequestError(e, r); err != nil {
 		t.Fatal(err)
 	}
 }
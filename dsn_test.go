@@ -0,0 +1,39 @@
+package bugsnag
+
+import "testing"
+
+func TestNewClientFromDSNDefaultsMatchProduction(t *testing.T) {
+	c, err := NewClientFromDSN("https://apikey@notify.bugsnag.com/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.ReleaseStage != "production" {
+		t.Fatalf("expected ReleaseStage %q, got %q", "production", c.ReleaseStage)
+	}
+	if len(c.NotifyReleaseStages) != 1 || c.NotifyReleaseStages[0] != "production" {
+		t.Fatalf("expected NotifyReleaseStages [production], got %v", c.NotifyReleaseStages)
+	}
+	if !c.shouldNotify(&Event{ReleaseStage: c.ReleaseStage}) {
+		t.Fatal("expected a client built from a DSN to be able to notify for its own ReleaseStage")
+	}
+}
+
+func TestNewClientFromDSNInvalidDSN(t *testing.T) {
+	if _, err := NewClientFromDSN("://not-a-url"); err == nil {
+		t.Fatal("expected an error for an invalid DSN")
+	}
+}
+
+func TestParseDSNRejectsMissingAPIKey(t *testing.T) {
+	c := &Client{}
+	if err := c.ParseDSN("https://notify.bugsnag.com/"); err == nil {
+		t.Fatal("expected an error for a DSN with no API key")
+	}
+}
+
+func TestParseDSNRejectsUnsupportedScheme(t *testing.T) {
+	c := &Client{}
+	if err := c.ParseDSN("ftp://apikey@notify.bugsnag.com/"); err == nil {
+		t.Fatal("expected an error for an unsupported scheme")
+	}
+}
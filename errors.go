@@ -0,0 +1,134 @@
+package bugsnag
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+
+	pkgerrors "github.com/pkg/errors"
+)
+
+// StackExtractor pulls a captured Stacktrace out of an error, for error
+// libraries that record their own stack at the point an error is created
+// or wrapped, rather than at notify-time. See RegisterStackExtractor.
+type StackExtractor func(error) []Stacktrace
+
+// RegisterStackExtractor installs an additional StackExtractor, tried
+// before the built-in github.com/pkg/errors support. Extractors run in
+// registration order; the first one to return a non-empty stack for a
+// given error wins.
+func (c *Client) RegisterStackExtractor(fn StackExtractor) {
+	c.stackExtractors = append(c.stackExtractors, fn)
+}
+
+// causer is the pre-Go-1.13 unwrap convention used by
+// github.com/pkg/errors.
+type causer interface {
+	Cause() error
+}
+
+// unwrapper is the stdlib (Go 1.13+) errors.Is/As unwrap convention.
+type unwrapper interface {
+	Unwrap() error
+}
+
+// unwrapOne returns the error that err wraps, or nil if it wraps nothing,
+// checking both the pkg/errors Cause() and the stdlib Unwrap() conventions.
+func unwrapOne(err error) error {
+	switch e := err.(type) {
+	case causer:
+		return e.Cause()
+	case unwrapper:
+		return e.Unwrap()
+	}
+	return nil
+}
+
+// extractStack tries every registered StackExtractor, then the built-in
+// github.com/pkg/errors convention, returning the first non-empty stack
+// found for err.
+func (c *Client) extractStack(err error) []Stacktrace {
+	for _, fn := range c.stackExtractors {
+		if frames := fn(err); len(frames) > 0 {
+			return frames
+		}
+	}
+	if st, ok := err.(interface{ StackTrace() pkgerrors.StackTrace }); ok {
+		return framesFromPkgErrors(st.StackTrace())
+	}
+	return nil
+}
+
+func framesFromPkgErrors(trace pkgerrors.StackTrace) []Stacktrace {
+	frames := make([]Stacktrace, 0, len(trace))
+	for _, f := range trace {
+		frames = append(frames, Stacktrace{
+			File:       frameFile(f),
+			LineNumber: frameLineNumber(f),
+			Method:     fmt.Sprintf("%n", f),
+			InProject:  true,
+		})
+	}
+	return frames
+}
+
+// frameFile returns f's full file path. The plain %s verb only yields
+// path.Base(file); the full path is only available via %+s, formatted as
+// "funcname\n\tfile", so pull the file back out of that.
+func frameFile(f pkgerrors.Frame) string {
+	full := fmt.Sprintf("%+s", f)
+	if i := strings.LastIndex(full, "\n\t"); i >= 0 {
+		return full[i+2:]
+	}
+	return full
+}
+
+func frameLineNumber(f pkgerrors.Frame) int {
+	var line int
+	fmt.Sscanf(fmt.Sprintf("%d", f), "%d", &line)
+	return line
+}
+
+// buildExceptions walks err's cause chain (via Cause()/Unwrap()), emitting
+// one Exception per wrapped error so the original throw site isn't lost
+// when errors are wrapped. Each exception uses its own captured stack, if
+// any; if nothing in the chain captured a stack, the outermost exception
+// falls back to the stack at the caller of New/NewWithContext, as New
+// always did -- however deep the wrapper chain between here and there is.
+//
+// github.com/pkg/errors.Wrap builds two chain links for a single call --
+// a *withStack carrying the frame and a *withMessage carrying the added
+// text underneath it -- and both report the identical Error() string.
+// Treat a link whose message exactly matches the one just emitted as the
+// same wrap, not a distinct exception; it only contributes a stack if the
+// emitted link didn't already have one.
+func (c *Client) buildExceptions(err error) []Exception {
+	var exceptions []Exception
+	foundStack := false
+
+	for current := err; current != nil; current = unwrapOne(current) {
+		stack := c.extractStack(current)
+		if len(stack) > 0 {
+			foundStack = true
+		}
+
+		if n := len(exceptions); n > 0 && exceptions[n-1].Message == current.Error() {
+			if len(exceptions[n-1].Stacktrace) == 0 && len(stack) > 0 {
+				exceptions[n-1].Stacktrace = stack
+			}
+			continue
+		}
+
+		exceptions = append(exceptions, Exception{
+			ErrorClass: reflect.TypeOf(current).String(),
+			Message:    current.Error(),
+			Stacktrace: stack,
+		})
+	}
+
+	if !foundStack && len(exceptions) > 0 {
+		exceptions[0].Stacktrace = callerStacktrace()
+	}
+
+	return exceptions
+}
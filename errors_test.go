@@ -0,0 +1,118 @@
+package bugsnag
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+
+	pkgerrors "github.com/pkg/errors"
+)
+
+// wrapperFrameNames are the internal frames whose presence as the *first*
+// stacktrace entry would mean callerStacktrace failed to skip past this
+// package's own wrapper functions. They are spelled as function() reports
+// them: the full function name with everything up to its first "." cut off.
+var wrapperFrameNames = map[string]bool{
+	"com/Mistobaan/bugsnag.New":                       true,
+	"com/Mistobaan/bugsnag.(*Client).New":             true,
+	"com/Mistobaan/bugsnag.NewWithContext":            true,
+	"com/Mistobaan/bugsnag.(*Client).NewWithContext":  true,
+	"com/Mistobaan/bugsnag.CapturePanic":              true,
+	"com/Mistobaan/bugsnag.buildExceptions":           true,
+	"com/Mistobaan/bugsnag.(*Client).buildExceptions": true,
+	"com/Mistobaan/bugsnag.callerStacktrace":          true,
+	"gopanic":                                         true,
+}
+
+func TestBuildExceptionsSkipsInternalFramesViaNew(t *testing.T) {
+	event := DefaultClient.New(errors.New("boom"))
+	if len(event.Exceptions) == 0 || len(event.Exceptions[0].Stacktrace) == 0 {
+		t.Fatal("expected a non-empty stacktrace")
+	}
+	if first := event.Exceptions[0].Stacktrace[0].Method; wrapperFrameNames[first] {
+		t.Fatalf("first frame is an internal wrapper, not the call site: %q", first)
+	}
+}
+
+func TestBuildExceptionsSkipsInternalFramesViaNewWithContext(t *testing.T) {
+	event := DefaultClient.NewWithContext(context.Background(), errors.New("boom"))
+	if len(event.Exceptions) == 0 || len(event.Exceptions[0].Stacktrace) == 0 {
+		t.Fatal("expected a non-empty stacktrace")
+	}
+	if first := event.Exceptions[0].Stacktrace[0].Method; wrapperFrameNames[first] {
+		t.Fatalf("first frame is an internal wrapper, not the call site: %q", first)
+	}
+}
+
+func TestCapturePanicSkipsItsOwnFrame(t *testing.T) {
+	var firstFrame string
+	prev := TraceFilterFunc
+	TraceFilterFunc = func(traces []Stacktrace) []Stacktrace {
+		if len(traces) > 0 {
+			firstFrame = traces[0].Method
+		}
+		return traces
+	}
+	defer func() { TraceFilterFunc = prev }()
+
+	r, err := http.NewRequest("GET", "http://example.test", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	func() {
+		defer func() { recover() }()
+		func() {
+			defer CapturePanic(r)
+			panic("boom")
+		}()
+	}()
+
+	if wrapperFrameNames[firstFrame] {
+		t.Fatalf("CapturePanic's own frame leaked into the trace: %q", firstFrame)
+	}
+}
+
+// TestBuildExceptionsCollapsesPkgErrorsWrap covers errors.Wrap's chain
+// shape directly: a single Wrap call produces a *withStack link and a
+// *withMessage link underneath it, both reporting the identical message.
+// buildExceptions must report that as one Exception, not two.
+func TestBuildExceptionsCollapsesPkgErrorsWrap(t *testing.T) {
+	root := pkgerrors.New("root cause")
+	wrapped := pkgerrors.Wrap(root, "context")
+
+	exceptions := DefaultClient.buildExceptions(wrapped)
+
+	if len(exceptions) != 2 {
+		t.Fatalf("expected 2 exceptions (wrap + root cause), got %d: %+v", len(exceptions), exceptions)
+	}
+	if exceptions[0].Message != "context: root cause" {
+		t.Fatalf("expected the outer exception's message to be %q, got %q", "context: root cause", exceptions[0].Message)
+	}
+	if exceptions[1].Message != "root cause" {
+		t.Fatalf("expected the inner exception's message to be %q, got %q", "root cause", exceptions[1].Message)
+	}
+	if len(exceptions[0].Stacktrace) == 0 {
+		t.Fatal("expected the collapsed wrap exception to carry the stack captured by Wrap")
+	}
+}
+
+// TestFrameFileReturnsFullPath confirms frameFile recovers the full path
+// rather than %s's path.Base(file) truncation.
+func TestFrameFileReturnsFullPath(t *testing.T) {
+	wrapped := pkgerrors.Wrap(pkgerrors.New("root cause"), "context")
+	st, ok := wrapped.(interface{ StackTrace() pkgerrors.StackTrace })
+	if !ok {
+		t.Fatal("expected wrapped error to implement StackTrace()")
+	}
+	trace := st.StackTrace()
+	if len(trace) == 0 {
+		t.Fatal("expected a non-empty StackTrace")
+	}
+	file := frameFile(trace[0])
+	if !strings.HasSuffix(file, "errors_test.go") || !strings.Contains(file, "/") {
+		t.Fatalf("expected a full path ending in errors_test.go, got %q", file)
+	}
+}
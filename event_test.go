@@ -0,0 +1,67 @@
+package bugsnag
+
+import "testing"
+
+func TestNewSetsPayloadVersion4(t *testing.T) {
+	event := DefaultClient.New(nil)
+	if event.PayloadVersion != "4" {
+		t.Fatalf("expected PayloadVersion %q, got %q", "4", event.PayloadVersion)
+	}
+}
+
+func TestWithSeveritySetsSeverity(t *testing.T) {
+	event := (&Event{}).WithSeverity(SeverityWarning)
+	if event.Severity != SeverityWarning {
+		t.Fatalf("expected severity %q, got %q", SeverityWarning, event.Severity)
+	}
+}
+
+func TestWithGroupingHashOverridesGrouping(t *testing.T) {
+	event := (&Event{}).WithGroupingHash("custom-hash")
+	if event.GroupingHash != "custom-hash" {
+		t.Fatalf("expected GroupingHash %q, got %q", "custom-hash", event.GroupingHash)
+	}
+}
+
+func TestWithUnhandledMarksEvent(t *testing.T) {
+	event := (&Event{}).WithUnhandled(true)
+	if !event.Unhandled {
+		t.Fatal("expected Unhandled to be true")
+	}
+}
+
+func TestWithUserSetsUser(t *testing.T) {
+	user := User{ID: "42", Email: "a@example.com", Name: "Ada"}
+	event := (&Event{}).WithUser(user)
+	if event.User == nil || *event.User != user {
+		t.Fatalf("expected User %+v, got %+v", user, event.User)
+	}
+}
+
+func TestWithUserIDSetsOnlyID(t *testing.T) {
+	event := (&Event{}).WithUserID("123")
+	if event.User == nil || event.User.ID != "123" {
+		t.Fatalf("expected User.ID %q, got %+v", "123", event.User)
+	}
+}
+
+func TestWithSessionSetsSession(t *testing.T) {
+	session := Session{ID: "abc123", StartedAt: "2026-07-27T00:00:00Z"}
+	event := (&Event{}).WithSession(session)
+	if event.Session == nil || *event.Session != session {
+		t.Fatalf("expected Session %+v, got %+v", session, event.Session)
+	}
+}
+
+func TestFluentSettersChain(t *testing.T) {
+	event := (&Event{}).
+		WithSeverity(SeverityError).
+		WithGroupingHash("hash").
+		WithUnhandled(true).
+		WithUser(User{ID: "1"}).
+		WithSession(Session{ID: "abc123"})
+
+	if event.Severity != SeverityError || event.GroupingHash != "hash" || !event.Unhandled || event.User.ID != "1" || event.Session.ID != "abc123" {
+		t.Fatalf("expected all fluent setters to apply, got %+v", event)
+	}
+}
@@ -0,0 +1,104 @@
+package bugsnag
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+)
+
+// ErrSkipEvent, returned by a BeforeNotify filter, drops the event
+// instead of sending it.
+var ErrSkipEvent = errors.New("bugsnag: event skipped by BeforeNotify filter")
+
+// runBeforeNotify runs the client's BeforeNotify filter chain, then
+// scrubs MetaData per ScrubKeys/ScrubParams. A filter returning
+// ErrSkipEvent drops the event; any other filter error aborts
+// notification entirely.
+func (c *Client) runBeforeNotify(event *Event) error {
+	for _, filter := range c.BeforeNotify {
+		if err := filter(event); err != nil {
+			return err
+		}
+	}
+	c.scrub(event)
+	return nil
+}
+
+// scrub walks event.MetaData and replaces any value keyed by ScrubKeys or
+// matching ScrubParams with "[FILTERED]". The raw *http.Request that
+// NotifyRequestError dumps under the "request"/"dump" tab is not walked
+// field by field: it cannot be marshalled, so scrubValue replaces it
+// whole with "[FILTERED]". That is what keeps NotifyRequestError from
+// leaking Authorization headers and form fields into Bugsnag unredacted.
+//
+// This always runs, even with no ScrubKeys/ScrubParams configured: the
+// JSON round-trip in scrubValue is also what turns values like the raw
+// *http.Request NotifyRequestError/CapturePanic attach -- which can never
+// json.Marshal as-is -- into something the payload encoder can send at
+// all. Skipping it by default would leave those two entry points unable
+// to deliver a single event out of the box.
+func (c *Client) scrub(event *Event) {
+	for tab, values := range event.MetaData {
+		for name, value := range values {
+			event.MetaData[tab][name] = c.scrubValue(name, value)
+		}
+	}
+}
+
+// scrubValue scrubs a single MetaData entry. Values that aren't already
+// plain JSON types (e.g. a *http.Request) are round-tripped through JSON
+// first, so nested maps and slices can be scrubbed the same way. A value
+// that can't be inspected this way (for example *http.Request, which
+// always fails to marshal because of its GetBody func field) is redacted
+// outright rather than passed through: scrubbing must fail closed, not
+// leak the original value just because it couldn't be parsed.
+func (c *Client) scrubValue(key string, value interface{}) interface{} {
+	if c.matchesScrub(key) {
+		return "[FILTERED]"
+	}
+
+	b, err := json.Marshal(value)
+	if err != nil {
+		return "[FILTERED]"
+	}
+	var generic interface{}
+	if err := json.Unmarshal(b, &generic); err != nil {
+		return "[FILTERED]"
+	}
+	return c.scrubGeneric(generic)
+}
+
+func (c *Client) scrubGeneric(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for k, val := range v {
+			if c.matchesScrub(k) {
+				v[k] = "[FILTERED]"
+			} else {
+				v[k] = c.scrubGeneric(val)
+			}
+		}
+		return v
+	case []interface{}:
+		for i, val := range v {
+			v[i] = c.scrubGeneric(val)
+		}
+		return v
+	default:
+		return v
+	}
+}
+
+func (c *Client) matchesScrub(key string) bool {
+	for _, k := range c.ScrubKeys {
+		if strings.EqualFold(k, key) {
+			return true
+		}
+	}
+	for _, re := range c.ScrubParams {
+		if re.MatchString(key) {
+			return true
+		}
+	}
+	return false
+}
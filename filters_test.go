@@ -0,0 +1,77 @@
+package bugsnag
+
+import (
+	"encoding/json"
+	"net/http"
+	"regexp"
+	"testing"
+)
+
+func TestScrubValueFiltersMatchingKeys(t *testing.T) {
+	c := &Client{ScrubKeys: []string{"password"}}
+	if got := c.scrubValue("password", "hunter2"); got != "[FILTERED]" {
+		t.Fatalf("expected matching key to be filtered, got %v", got)
+	}
+}
+
+func TestScrubValueFiltersNestedKeys(t *testing.T) {
+	c := &Client{ScrubParams: []*regexp.Regexp{regexp.MustCompile("(?i)token")}}
+	value := map[string]interface{}{
+		"auth_token": "secret",
+		"nested": map[string]interface{}{
+			"Token": "also-secret",
+		},
+	}
+	scrubbed := c.scrubValue("details", value).(map[string]interface{})
+	if scrubbed["auth_token"] != "[FILTERED]" {
+		t.Fatalf("expected top-level token to be filtered, got %+v", scrubbed)
+	}
+	nested := scrubbed["nested"].(map[string]interface{})
+	if nested["Token"] != "[FILTERED]" {
+		t.Fatalf("expected nested token to be filtered, got %+v", nested)
+	}
+}
+
+// TestScrubValueFailsClosedOnMarshalError exercises the fallback path: a
+// *http.Request always fails json.Marshal (its GetBody field has a func
+// type, which json.Marshal rejects even when the field is nil, as it is
+// here), which is exactly the value NotifyRequestError/CapturePanic
+// attach under the "request"/"dump" tab. Scrubbing must redact it rather
+// than pass the unscrubbed original through.
+func TestScrubValueFailsClosedOnMarshalError(t *testing.T) {
+	c := &Client{ScrubKeys: []string{"Authorization"}}
+	r, err := http.NewRequest("GET", "http://example.test", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r.Header.Set("Authorization", "Bearer secret")
+
+	if _, marshalErr := json.Marshal(r); marshalErr == nil {
+		t.Fatal("expected *http.Request to fail json.Marshal; test assumption no longer holds")
+	}
+
+	if got := c.scrubValue("dump", r); got != "[FILTERED]" {
+		t.Fatalf("expected unmarshalable value to fail closed as [FILTERED], got %v", got)
+	}
+}
+
+// TestScrubSanitizesRequestDumpWithNoScrubConfig confirms scrub() always
+// runs the marshal-safety round-trip, even on a Client with no
+// ScrubKeys/ScrubParams configured (the zero value, as on DefaultClient).
+// Without this, NotifyRequestError/CapturePanic's raw *http.Request under
+// MetaData["request"]["dump"] would reach encode() unsanitized and fail
+// every send with "json: unsupported type: func() (io.ReadCloser, error)".
+func TestScrubSanitizesRequestDumpWithNoScrubConfig(t *testing.T) {
+	c := &Client{}
+	r, err := http.NewRequest("GET", "http://example.test", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	event := (&Event{}).WithMetaData("request", "dump", r)
+	c.scrub(event)
+
+	if _, marshalErr := json.Marshal(event); marshalErr != nil {
+		t.Fatalf("expected scrub to make the event marshalable even with no scrub config, got error: %v", marshalErr)
+	}
+}
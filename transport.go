@@ -0,0 +1,141 @@
+package bugsnag
+
+import (
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"math/rand"
+	"net/http"
+	"time"
+)
+
+const (
+	defaultTimeout    = 10 * time.Second
+	defaultMaxRetries = 3
+
+	// compressionThreshold is the payload size, in bytes, above which the
+	// body is gzip-compressed before sending, to keep large stacktraces
+	// under the ingest size limit.
+	compressionThreshold = 1024
+)
+
+// PermanentError is returned by send when the Bugsnag API rejects a
+// payload outright (400, 401, 413 or 422): retrying would not help, as
+// opposed to a transient network or 5xx failure.
+type PermanentError struct {
+	StatusCode int
+}
+
+func (e *PermanentError) Error() string {
+	return fmt.Sprintf("bugsnag: request rejected (status %d), not retrying", e.StatusCode)
+}
+
+func isPermanentStatus(code int) bool {
+	switch code {
+	case http.StatusBadRequest, http.StatusUnauthorized, http.StatusRequestEntityTooLarge, http.StatusUnprocessableEntity:
+		return true
+	}
+	return false
+}
+
+func (c *Client) httpClient() *http.Client {
+	if c.HTTPClient != nil {
+		return c.HTTPClient
+	}
+	return &http.Client{Timeout: defaultTimeout}
+}
+
+func (c *Client) maxRetries() int {
+	if c.MaxRetries > 0 {
+		return c.MaxRetries
+	}
+	return defaultMaxRetries
+}
+
+// compress gzip-encodes b when it is large enough to be worth it, matching
+// what other error-reporting SDKs do to fit large stacktraces under ingest
+// limits. It reports whether compression was applied.
+func compress(b []byte) ([]byte, bool, error) {
+	if len(b) <= compressionThreshold {
+		return b, false, nil
+	}
+
+	var buf bytes.Buffer
+	w := gzip.NewWriter(&buf)
+	if _, err := w.Write(b); err != nil {
+		return nil, false, err
+	}
+	if err := w.Close(); err != nil {
+		return nil, false, err
+	}
+	return buf.Bytes(), true, nil
+}
+
+// backoff returns the delay before retry attempt n (1-indexed): an
+// exponentially growing base with up to 50% jitter, to avoid a thundering
+// herd of retries.
+func backoff(attempt int) time.Duration {
+	base := time.Duration(1<<uint(attempt-1)) * 200 * time.Millisecond
+	jitter := time.Duration(rand.Int63n(int64(base)/2 + 1))
+	return base + jitter
+}
+
+func (c *Client) send(events []*Event) error {
+
+	if c.APIKey == "" {
+		return fmt.Errorf("No Api Key Provided")
+	}
+
+	payload := &Payload{
+		Notifier: c.Notifier,
+		APIKey:   c.APIKey,
+		Events:   events,
+	}
+
+	protocol := "http://"
+	if c.UseSSL {
+		protocol = "https://"
+	}
+
+	b, err := encode(payload, c.Indent)
+	if err != nil {
+		return err
+	}
+
+	body, gzipped, err := compress(b)
+	if err != nil {
+		return err
+	}
+
+	var lastErr error
+	for attempt := 1; attempt <= c.maxRetries()+1; attempt++ {
+		if attempt > 1 {
+			time.Sleep(backoff(attempt - 1))
+		}
+
+		req, err := http.NewRequest("POST", protocol+c.Url, bytes.NewReader(body))
+		if err != nil {
+			return err
+		}
+		req.Header.Set("Content-Type", applicationJson)
+		if gzipped {
+			req.Header.Set("Content-Encoding", "gzip")
+		}
+
+		resp, err := c.httpClient().Do(req)
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		resp.Body.Close()
+
+		if resp.StatusCode == http.StatusOK {
+			return nil
+		}
+		if isPermanentStatus(resp.StatusCode) {
+			return &PermanentError{StatusCode: resp.StatusCode}
+		}
+		lastErr = fmt.Errorf("Unexpected status code: %d", resp.StatusCode)
+	}
+	return lastErr
+}
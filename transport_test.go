@@ -0,0 +1,155 @@
+package bugsnag
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func newSendTestClient(url string) *Client {
+	return &Client{
+		APIKey:       "testkey",
+		Notifier:     DefaultNotifier,
+		ReleaseStage: "production",
+		UseSSL:       false,
+		Url:          strings.TrimPrefix(url, "http://"),
+		HTTPClient:   http.DefaultClient,
+	}
+}
+
+func TestSendRequiresAPIKey(t *testing.T) {
+	c := &Client{}
+	if err := c.send([]*Event{{}}); err == nil {
+		t.Fatal("expected an error when APIKey is empty")
+	}
+}
+
+func TestSendSucceedsOnOK(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	c := newSendTestClient(server.URL)
+	if err := c.send([]*Event{c.New(nil)}); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestSendReturnsPermanentErrorWithoutRetrying(t *testing.T) {
+	var attempts int64
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt64(&attempts, 1)
+		w.WriteHeader(http.StatusUnauthorized)
+	}))
+	defer server.Close()
+
+	c := newSendTestClient(server.URL)
+	err := c.send([]*Event{c.New(nil)})
+	if _, ok := err.(*PermanentError); !ok {
+		t.Fatalf("expected a *PermanentError, got %v (%T)", err, err)
+	}
+	if got := atomic.LoadInt64(&attempts); got != 1 {
+		t.Fatalf("expected exactly 1 attempt for a permanent error, got %d", got)
+	}
+}
+
+func TestSendRetriesTransientErrors(t *testing.T) {
+	var attempts int64
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := atomic.AddInt64(&attempts, 1)
+		if n < 3 {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	c := newSendTestClient(server.URL)
+	c.MaxRetries = 3
+	if err := c.send([]*Event{c.New(nil)}); err != nil {
+		t.Fatal(err)
+	}
+	if got := atomic.LoadInt64(&attempts); got != 3 {
+		t.Fatalf("expected 3 attempts (2 failures + 1 success), got %d", got)
+	}
+}
+
+func TestSendGivesUpAfterMaxRetries(t *testing.T) {
+	var attempts int64
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt64(&attempts, 1)
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer server.Close()
+
+	c := newSendTestClient(server.URL)
+	c.MaxRetries = 2
+	if err := c.send([]*Event{c.New(nil)}); err == nil {
+		t.Fatal("expected an error once retries are exhausted")
+	}
+	if got := atomic.LoadInt64(&attempts); got != 3 {
+		t.Fatalf("expected MaxRetries+1 = 3 attempts, got %d", got)
+	}
+}
+
+func TestSendGzipsLargePayloads(t *testing.T) {
+	var gotEncoding string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotEncoding = r.Header.Get("Content-Encoding")
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	c := newSendTestClient(server.URL)
+	event := c.New(nil)
+	event.WithMetaData("big", "blob", strings.Repeat("x", compressionThreshold*2))
+	if err := c.send([]*Event{event}); err != nil {
+		t.Fatal(err)
+	}
+	if gotEncoding != "gzip" {
+		t.Fatalf("expected a gzip-compressed request for a large payload, got Content-Encoding=%q", gotEncoding)
+	}
+}
+
+func TestSendDoesNotGzipSmallPayloads(t *testing.T) {
+	var gotEncoding string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotEncoding = r.Header.Get("Content-Encoding")
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	c := newSendTestClient(server.URL)
+	if err := c.send([]*Event{c.New(nil)}); err != nil {
+		t.Fatal(err)
+	}
+	if gotEncoding != "" {
+		t.Fatalf("expected no Content-Encoding for a small payload, got %q", gotEncoding)
+	}
+}
+
+func TestHTTPClientDefaultsWhenUnset(t *testing.T) {
+	c := &Client{}
+	if got := c.httpClient(); got == nil || got.Timeout != defaultTimeout {
+		t.Fatalf("expected a default client with Timeout=%v, got %+v", defaultTimeout, got)
+	}
+}
+
+func TestHTTPClientUsesConfiguredClient(t *testing.T) {
+	custom := &http.Client{Timeout: time.Second}
+	c := &Client{HTTPClient: custom}
+	if got := c.httpClient(); got != custom {
+		t.Fatalf("expected httpClient() to return the configured *http.Client")
+	}
+}
+
+func TestBackoffGrowsWithAttempt(t *testing.T) {
+	if backoff(1) >= backoff(3) {
+		t.Fatalf("expected backoff to grow with attempt number: backoff(1)=%v backoff(3)=%v", backoff(1), backoff(3))
+	}
+}